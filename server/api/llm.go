@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"go-websocket-server/utils"
+)
+
+const groqChatCompletionsURL = "https://api.groq.com/openai/v1/chat/completions"
+
+type groqChatRequest struct {
+	Model    string            `json:"model"`
+	Messages []groqChatMessage `json:"messages"`
+	Stream   bool              `json:"stream"`
+}
+
+type groqChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type groqStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// AskLlama streams a completion for text from Groq's Llama endpoint, pushing
+// each token onto botTextForClient and botTextForTTS as it arrives, and
+// closing both channels once the stream ends. If ctx is cancelled (e.g. the
+// user barges in), the Groq request is aborted and both channels are closed
+// without waiting for the rest of the reply. The conversation's persisted
+// history is prepended to the prompt so multi-turn context survives page
+// reloads, and the assistant's full reply is persisted in turn once it
+// completes uninterrupted.
+func AskLlama(ctx context.Context, conversationID string, text string, botTextForClient chan string, botTextForTTS chan string) {
+	defer close(botTextForClient)
+	defer close(botTextForTTS)
+
+	history, err := utils.LoadConversation(conversationID)
+	if err != nil {
+		log.Println("Error loading conversation history:", err)
+	}
+	messages := make([]groqChatMessage, 0, len(history)+1)
+	for _, m := range history {
+		role := m.Role
+		if role != "assistant" && role != "system" {
+			role = "user"
+		}
+		messages = append(messages, groqChatMessage{Role: role, Content: m.Text})
+	}
+	// The caller may have already persisted this turn (e.g. a voice turn's
+	// transcript, saved so its word timings survive) before calling us, in
+	// which case history's last entry already carries it and appending text
+	// again would send it to Llama twice. Otherwise (e.g. a typed message) it
+	// is persisted here so it is part of the history on the next turn.
+	if last := len(history) - 1; last < 0 || history[last].Role != "user" || history[last].Text != text {
+		messages = append(messages, groqChatMessage{Role: "user", Content: text})
+		if err := utils.SaveMessage(conversationID, "user", text, nil); err != nil {
+			log.Println("Error saving user message:", err)
+		}
+	}
+
+	reqBody, err := json.Marshal(groqChatRequest{
+		Model:    "llama-3.3-70b-versatile",
+		Messages: messages,
+		Stream:   true,
+	})
+	if err != nil {
+		log.Println("Error building Groq request:", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, groqChatCompletionsURL, bytes.NewReader(reqBody))
+	if err != nil {
+		log.Println("Error creating Groq request:", err)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("GROQ_API_KEY"))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Println("Error calling Groq:", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return
+		}
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		payload := strings.TrimPrefix(line, "data: ")
+		if payload == "[DONE]" {
+			break
+		}
+		var chunk groqStreamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			reply.WriteString(choice.Delta.Content)
+			select {
+			case <-ctx.Done():
+				return
+			case botTextForClient <- choice.Delta.Content:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case botTextForTTS <- choice.Delta.Content:
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("Error reading Groq stream:", err)
+		return
+	}
+
+	if err := utils.SaveMessage(conversationID, "assistant", reply.String(), nil); err != nil {
+		log.Println("Error saving assistant message:", err)
+	}
+}
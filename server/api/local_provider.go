@@ -0,0 +1,163 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LocalSTTProvider is a MOCK STTProvider: it satisfies the interface so
+// STT_PROVIDER=local is wireable end-to-end, but whisper-cli has no mode
+// that reads a raw PCM stream off stdin and emits newline-delimited
+// {"text","is_final"} JSON the way StreamAudio assumes below - that's not
+// whisper.cpp's actual CLI or server protocol. Treat this as a stand-in for
+// a real self-hosted backend (e.g. whisper.cpp's HTTP server and its
+// /inference endpoint) until someone wires one up; it will not transcribe
+// anything today.
+type LocalSTTProvider struct {
+	binaryPath string
+	modelPath  string
+	cmd        *exec.Cmd
+}
+
+// NewLocalSTTProvider returns a LocalSTTProvider configured from the
+// WHISPER_BINARY/WHISPER_MODEL environment variables, falling back to
+// sensible defaults for a local whisper.cpp checkout. See LocalSTTProvider's
+// doc comment: this is a mock, not a working whisper.cpp integration.
+func NewLocalSTTProvider() *LocalSTTProvider {
+	log.Println("WARNING: STT_PROVIDER=local is a mock and will not actually transcribe audio; see LocalSTTProvider's doc comment")
+	return &LocalSTTProvider{
+		binaryPath: envOrDefault("WHISPER_BINARY", "whisper-cli"),
+		modelPath:  envOrDefault("WHISPER_MODEL", "models/ggml-base.en.bin"),
+	}
+}
+
+// StreamAudio pipes chunks into whisper.cpp's stdin and parses its
+// newline-delimited JSON transcripts off stdout. Mocked out pending a real
+// protocol; see LocalSTTProvider's doc comment.
+func (p *LocalSTTProvider) StreamAudio(ctx context.Context, chunks <-chan []byte) (<-chan Transcript, error) {
+	cmd := exec.CommandContext(ctx, p.binaryPath, "--model", p.modelPath, "--stream", "--output-json")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p.cmd = cmd
+
+	go func() {
+		for chunk := range chunks {
+			if _, err := stdin.Write(chunk); err != nil {
+				return
+			}
+		}
+		stdin.Close()
+	}()
+
+	transcripts := make(chan Transcript)
+	go func() {
+		defer close(transcripts)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			var line struct {
+				Text    string `json:"text"`
+				IsFinal bool   `json:"is_final"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				continue
+			}
+			transcripts <- Transcript{Text: line.Text, IsFinal: line.IsFinal}
+		}
+	}()
+	return transcripts, nil
+}
+
+// Finalize sends the whisper-cli process an interrupt signal (SIGINT).
+func (p *LocalSTTProvider) Finalize() {
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Signal(os.Interrupt)
+	}
+}
+
+// Close kills the whisper.cpp process.
+func (p *LocalSTTProvider) Close() error {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return nil
+	}
+	return p.cmd.Process.Kill()
+}
+
+// LocalTTSProvider is a MOCK TTSProvider: it satisfies the interface so
+// TTS_PROVIDER=local is wireable end-to-end, but spawning Piper once per
+// LLM token (see synthesizeToken below) hands it a word fragment, not a
+// synthesizable sentence, and Piper's real protocol is a single long-lived
+// process reading one line of text per utterance from stdin rather than a
+// fresh process per call. Treat this as a stand-in for a real self-hosted
+// backend until someone wires one up properly; it will not produce usable
+// speech today.
+type LocalTTSProvider struct {
+	binaryPath string
+	voicePath  string
+}
+
+// NewLocalTTSProvider returns a LocalTTSProvider configured from the
+// PIPER_BINARY/PIPER_VOICE environment variables, falling back to sensible
+// defaults for a local Piper checkout. See LocalTTSProvider's doc comment:
+// this is a mock, not a working Piper integration.
+func NewLocalTTSProvider() *LocalTTSProvider {
+	log.Println("WARNING: TTS_PROVIDER=local is a mock and will not actually synthesize speech; see LocalTTSProvider's doc comment")
+	return &LocalTTSProvider{
+		binaryPath: envOrDefault("PIPER_BINARY", "piper"),
+		voicePath:  envOrDefault("PIPER_VOICE", "models/en_US-lessac-medium.onnx"),
+	}
+}
+
+// Synthesize runs Piper once per token. Mocked out pending a real protocol;
+// see LocalTTSProvider's doc comment.
+func (p *LocalTTSProvider) Synthesize(ctx context.Context, text <-chan string) (<-chan []byte, error) {
+	botAudio := make(chan []byte)
+	go func() {
+		defer close(botAudio)
+		for token := range text {
+			audio, err := p.synthesizeToken(ctx, token)
+			if err != nil {
+				log.Println("Error synthesizing with Piper:", err)
+				continue
+			}
+			botAudio <- audio
+		}
+	}()
+	return botAudio, nil
+}
+
+func (p *LocalTTSProvider) synthesizeToken(ctx context.Context, token string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, p.binaryPath, "--model", p.voicePath, "--output-raw")
+	cmd.Stdin = strings.NewReader(token)
+	return cmd.Output()
+}
+
+// Finalize is a no-op: Piper has no buffered session to flush.
+func (p *LocalTTSProvider) Finalize() {}
+
+// Clear is a no-op: Piper is invoked per-token, so there's nothing buffered
+// to discard on barge-in.
+func (p *LocalTTSProvider) Clear() {}
+
+// Close is a no-op: Piper is invoked per-token and leaves nothing running.
+func (p *LocalTTSProvider) Close() error { return nil }
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+
+	"go-websocket-server/utils"
+)
+
+// Transcript is one chunk of speech-to-text output produced by an
+// STTProvider's StreamAudio channel, or the aggregate of all of a turn's
+// finalized chunks (see SendTranscriptToClient).
+type Transcript struct {
+	Text    string
+	IsFinal bool
+	Words   []utils.Word
+	Channel int
+}
+
+// STTProvider streams raw audio chunks in and transcripts out for a single
+// turn. The channel returned by StreamAudio closes once the provider has
+// nothing left to send, either because Finalize has drained the last
+// results or because Close was called.
+type STTProvider interface {
+	StreamAudio(ctx context.Context, chunks <-chan []byte) (<-chan Transcript, error)
+	// Finalize asks the provider to flush any buffered audio into a final
+	// transcript and close out the stream.
+	Finalize()
+	Close() error
+}
+
+// TTSProvider turns streamed text tokens into streamed audio frames for a
+// single turn. The channel returned by Synthesize closes once the provider
+// has nothing left to send.
+type TTSProvider interface {
+	Synthesize(ctx context.Context, text <-chan string) (<-chan []byte, error)
+	// Finalize asks the provider to flush any buffered audio synthesized so
+	// far, e.g. at a sentence boundary.
+	Finalize()
+	// Clear discards any buffered/in-flight synthesis, e.g. because the user
+	// barged in and the client is about to flush its playback queue.
+	Clear()
+	Close() error
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"os"
+
+	"go-websocket-server/utils"
+)
+
+// NewSTTProvider selects a speech-to-text backend based on the
+// STT_PROVIDER environment variable. Deepgram is the default; "local"
+// selects LocalSTTProvider, a mock that does not transcribe audio.
+func NewSTTProvider() (STTProvider, error) {
+	switch provider := os.Getenv("STT_PROVIDER"); provider {
+	case "", "deepgram":
+		return NewDeepgramSTTProvider(DefaultDeepgramOptions()), nil
+	case "local":
+		return NewLocalSTTProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown STT_PROVIDER %q", provider)
+	}
+}
+
+// NewTTSProvider selects a text-to-speech backend based on the
+// TTS_PROVIDER environment variable. Deepgram is the default; "local"
+// selects LocalTTSProvider, a mock that does not produce usable speech.
+func NewTTSProvider(writeChan chan utils.WebSocketPacket) (TTSProvider, error) {
+	switch provider := os.Getenv("TTS_PROVIDER"); provider {
+	case "", "deepgram":
+		return NewDeepgramTTSProvider(writeChan), nil
+	case "local":
+		return NewLocalTTSProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown TTS_PROVIDER %q", provider)
+	}
+}
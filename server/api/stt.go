@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const deepgramListenURL = "wss://api.deepgram.com/v1/listen?encoding=linear16&sample_rate=16000&channels=1"
+
+// DeepgramOptions configures the keepalive and auto-finalize behavior of a
+// DeepgramSTTProvider.
+type DeepgramOptions struct {
+	// KeepAliveInterval is how often a {"type":"KeepAlive"} message is sent,
+	// so Deepgram doesn't time out the stream during pauses in speech. It is
+	// sent on every tick, whether or not audio frames are flowing.
+	KeepAliveInterval time.Duration
+	// AutoFinalizeSilence is how long to wait since the last binary audio
+	// frame before auto-finalizing, so a turn can close out without the
+	// browser sending an explicit audioEnd.
+	AutoFinalizeSilence time.Duration
+	// FinalizeDrain is how long to wait after Finalize for Deepgram's
+	// trailing final results before closing the transcript stream.
+	FinalizeDrain time.Duration
+}
+
+// DefaultDeepgramOptions returns the keepalive/auto-finalize timings used in
+// production.
+func DefaultDeepgramOptions() DeepgramOptions {
+	return DeepgramOptions{
+		KeepAliveInterval:   5 * time.Second,
+		AutoFinalizeSilence: 2000 * time.Millisecond,
+		FinalizeDrain:       1500 * time.Millisecond,
+	}
+}
+
+// DeepgramSTTProvider implements STTProvider against Deepgram's streaming
+// /v1/listen websocket.
+type DeepgramSTTProvider struct {
+	options      DeepgramOptions
+	conn         *websocket.Conn
+	writeMu      sync.Mutex
+	lastActivity chan struct{}
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewDeepgramSTTProvider returns a DeepgramSTTProvider ready to have
+// StreamAudio called on it.
+func NewDeepgramSTTProvider(options DeepgramOptions) *DeepgramSTTProvider {
+	return &DeepgramSTTProvider{
+		options:      options,
+		lastActivity: make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}
+}
+
+// StreamAudio dials Deepgram's streaming STT endpoint, forwards chunks to it
+// as they arrive, and returns the transcripts Deepgram sends back.
+func (p *DeepgramSTTProvider) StreamAudio(ctx context.Context, chunks <-chan []byte) (<-chan Transcript, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Token "+os.Getenv("DEEPGRAM_API_KEY"))
+
+	conn, _, err := websocket.DefaultDialer.Dial(deepgramListenURL, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial Deepgram: %w", err)
+	}
+	p.conn = conn
+
+	go p.forwardChunks(chunks)
+	go p.keepAliveLoop()
+	go p.autoFinalizeLoop()
+
+	transcripts := make(chan Transcript)
+	go p.readTranscripts(transcripts)
+	return transcripts, nil
+}
+
+// writeMessage serializes every write to conn: gorilla/websocket permits
+// only one concurrent writer, and forwardChunks, keepAliveLoop, and
+// Finalize all write to the same connection from separate goroutines.
+func (p *DeepgramSTTProvider) writeMessage(messageType int, data []byte) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return p.conn.WriteMessage(messageType, data)
+}
+
+func (p *DeepgramSTTProvider) forwardChunks(chunks <-chan []byte) {
+	for chunk := range chunks {
+		select {
+		case p.lastActivity <- struct{}{}:
+		default:
+		}
+		if err := p.writeMessage(websocket.BinaryMessage, chunk); err != nil {
+			log.Println("Error writing audio to Deepgram:", err)
+			return
+		}
+	}
+}
+
+func (p *DeepgramSTTProvider) keepAliveLoop() {
+	ticker := time.NewTicker(p.options.KeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.writeMessage(websocket.TextMessage, []byte(`{"type":"KeepAlive"}`))
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *DeepgramSTTProvider) autoFinalizeLoop() {
+	// Don't start the silence countdown until the first audio frame arrives:
+	// arming it at stream open would auto-finalize (and close the stream)
+	// out from under a user who simply hasn't started speaking yet.
+	select {
+	case <-p.lastActivity:
+	case <-p.stop:
+		return
+	}
+
+	timer := time.NewTimer(p.options.AutoFinalizeSilence)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.lastActivity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(p.options.AutoFinalizeSilence)
+		case <-timer.C:
+			p.Finalize()
+			return
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *DeepgramSTTProvider) readTranscripts(transcripts chan Transcript) {
+	defer close(transcripts)
+	for {
+		_, message, err := p.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		t, err := parseTranscript(message)
+		if err != nil || t.Text == "" {
+			continue
+		}
+		transcripts <- t
+	}
+}
+
+// Finalize asks Deepgram to flush any buffered audio into a final
+// transcript, then closes the stream after a short drain window so the
+// trailing results have time to arrive.
+func (p *DeepgramSTTProvider) Finalize() {
+	if p.conn == nil {
+		return
+	}
+	p.writeMessage(websocket.TextMessage, []byte(`{"type":"Finalize"}`))
+	time.AfterFunc(p.options.FinalizeDrain, func() { p.Close() })
+}
+
+// Close stops the keepalive/auto-finalize goroutines and closes the
+// underlying websocket, which in turn unblocks readTranscripts.
+func (p *DeepgramSTTProvider) Close() error {
+	var err error
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		if p.conn != nil {
+			err = p.conn.Close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go-websocket-server/utils"
+)
+
+// deepgramResult mirrors the subset of Deepgram's streaming Results payload
+// that we currently care about, including per-word timing and confidence.
+type deepgramResult struct {
+	Channel struct {
+		Alternatives []struct {
+			Transcript string `json:"transcript"`
+			Words      []struct {
+				Word           string  `json:"word"`
+				Start          float64 `json:"start"`
+				End            float64 `json:"end"`
+				Confidence     float64 `json:"confidence"`
+				PunctuatedWord string  `json:"punctuated_word"`
+			} `json:"words"`
+		} `json:"alternatives"`
+	} `json:"channel"`
+	ChannelIndex []int `json:"channel_index"`
+	IsFinal      bool  `json:"is_final"`
+}
+
+func parseTranscript(raw []byte) (Transcript, error) {
+	var result deepgramResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return Transcript{}, err
+	}
+	if len(result.Channel.Alternatives) == 0 {
+		return Transcript{}, nil
+	}
+
+	alt := result.Channel.Alternatives[0]
+	words := make([]utils.Word, 0, len(alt.Words))
+	for _, w := range alt.Words {
+		words = append(words, utils.Word{
+			Start:      w.Start,
+			End:        w.End,
+			Confidence: w.Confidence,
+			Punctuated: w.PunctuatedWord,
+		})
+	}
+
+	channel := 0
+	if len(result.ChannelIndex) > 0 {
+		channel = result.ChannelIndex[0]
+	}
+
+	return Transcript{
+		Text:    alt.Transcript,
+		IsFinal: result.IsFinal,
+		Words:   words,
+		Channel: channel,
+	}, nil
+}
+
+// SendTranscriptToClient relays each transcript chunk to the frontend as an
+// "interim" or "final" packet as soon as it arrives, tagged with
+// conversationID so the client can associate live transcripts with a
+// conversation, and assembles the finalized chunks (text and word-level
+// data) into a single Transcript that it pushes onto userMessage once the
+// transcripts channel is closed.
+func SendTranscriptToClient(transcripts <-chan Transcript, userMessage chan<- Transcript, writeChan chan utils.WebSocketPacket, conversationID string) {
+	var fullText strings.Builder
+	var words []utils.Word
+
+	for t := range transcripts {
+		if t.Text == "" {
+			continue
+		}
+
+		packetType := "interim"
+		if t.IsFinal {
+			packetType = "final"
+			fullText.WriteString(t.Text)
+			fullText.WriteString(" ")
+			words = append(words, t.Words...)
+		}
+
+		writeChan <- utils.WebSocketPacket{
+			Type:           packetType,
+			Text:           t.Text,
+			ConversationID: conversationID,
+			Words:          t.Words,
+			Channel:        t.Channel,
+		}
+	}
+
+	userMessage <- Transcript{
+		Text:    strings.TrimSpace(fullText.String()),
+		IsFinal: true,
+		Words:   words,
+	}
+}
+
+// SendTextToClient streams the bot's reply tokens to the frontend as they
+// arrive from the LLM, stopping early if ctx is cancelled (e.g. the user
+// barged in on the current bot turn).
+func SendTextToClient(ctx context.Context, botTextForClient chan string, writeChan chan utils.WebSocketPacket) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token, ok := <-botTextForClient:
+			if !ok {
+				return
+			}
+			writeChan <- utils.WebSocketPacket{Type: "botMessage", Text: token}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	msginterfaces "github.com/deepgram/deepgram-go-sdk/pkg/api/speak/v1/websocket/interfaces"
+	interfaces "github.com/deepgram/deepgram-go-sdk/pkg/client/interfaces/v1"
+	speak "github.com/deepgram/deepgram-go-sdk/pkg/client/speak"
+
+	"go-websocket-server/utils"
+)
+
+// ttsCallback adapts Deepgram Speak WebSocket events onto our internal
+// channels: synthesized audio frames go to botAudio, and Metadata/Flush
+// events become control packets the frontend uses to render playback
+// markers. ctx is the owning turn's context, so Binary can stop trying to
+// hand off audio once SendAudioToClient has already walked away on
+// barge-in, and closeOnce keeps Close idempotent against the SDK calling it
+// more than once.
+type ttsCallback struct {
+	ctx       context.Context
+	botAudio  chan []byte
+	writeChan chan utils.WebSocketPacket
+	closeOnce sync.Once
+}
+
+func (c *ttsCallback) Open(or *msginterfaces.OpenResponse) error {
+	return nil
+}
+
+func (c *ttsCallback) Metadata(md *msginterfaces.MetadataResponse) error {
+	c.writeChan <- utils.WebSocketPacket{Type: "tts_metadata", Text: md.RequestID}
+	return nil
+}
+
+func (c *ttsCallback) Binary(byMsg []byte) error {
+	select {
+	case c.botAudio <- byMsg:
+	case <-c.ctx.Done():
+	}
+	return nil
+}
+
+func (c *ttsCallback) Flush(fr *msginterfaces.FlushedResponse) error {
+	c.writeChan <- utils.WebSocketPacket{Type: "tts_flushed"}
+	return nil
+}
+
+func (c *ttsCallback) Clear(cr *msginterfaces.ClearedResponse) error {
+	return nil
+}
+
+func (c *ttsCallback) Warning(wr *msginterfaces.WarningResponse) error {
+	log.Println("Deepgram Speak warning:", wr.WarnMsg)
+	return nil
+}
+
+func (c *ttsCallback) Error(er *msginterfaces.ErrorResponse) error {
+	log.Println("Deepgram Speak error:", er.ErrMsg)
+	return nil
+}
+
+func (c *ttsCallback) UnhandledEvent(byMsg []byte) error {
+	return nil
+}
+
+func (c *ttsCallback) Close(cr *msginterfaces.CloseResponse) error {
+	c.closeOnce.Do(func() { close(c.botAudio) })
+	return nil
+}
+
+// DeepgramTTSProvider implements TTSProvider against Deepgram's Speak
+// streaming websocket.
+type DeepgramTTSProvider struct {
+	writeChan chan utils.WebSocketPacket
+	client    *speak.WSCallback
+}
+
+// NewDeepgramTTSProvider returns a DeepgramTTSProvider ready to have
+// Synthesize called on it.
+func NewDeepgramTTSProvider(writeChan chan utils.WebSocketPacket) *DeepgramTTSProvider {
+	return &DeepgramTTSProvider{writeChan: writeChan}
+}
+
+// Synthesize opens a Deepgram Speak websocket and streams LLM tokens to it
+// as they arrive rather than batching a full reply before requesting audio,
+// flushing at sentence boundaries so playback can start well before the bot
+// has finished generating its response.
+func (p *DeepgramTTSProvider) Synthesize(ctx context.Context, text <-chan string) (<-chan []byte, error) {
+	options := &interfaces.WSSpeakOptions{
+		Model:      "aura-asteria-en",
+		Encoding:   "linear16",
+		SampleRate: 24000,
+	}
+	botAudio := make(chan []byte)
+	callback := &ttsCallback{ctx: ctx, botAudio: botAudio, writeChan: p.writeChan}
+
+	client, err := speak.NewWSUsingCallback(ctx, "", &interfaces.ClientOptions{}, options, callback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Deepgram Speak client: %w", err)
+	}
+	if ok := client.Connect(); !ok {
+		return nil, fmt.Errorf("failed to connect to Deepgram Speak websocket")
+	}
+	p.client = client
+
+	go p.streamTokens(ctx, text)
+	return botAudio, nil
+}
+
+func (p *DeepgramTTSProvider) streamTokens(ctx context.Context, text <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			p.Clear()
+			p.Close()
+			return
+		case token, ok := <-text:
+			if !ok {
+				// The reply may not end in terminal punctuation (a list, a
+				// trailing clause, a cut-off token), so endsSentence may
+				// never have flushed the last of it. Deepgram only
+				// synthesizes buffered text on Flush, so without this the
+				// tail of the reply is silently dropped.
+				p.Finalize()
+				p.Close()
+				return
+			}
+			if err := p.client.Speak(token); err != nil {
+				log.Println("Error sending token to Deepgram Speak:", err)
+				continue
+			}
+			if endsSentence(token) {
+				p.client.Flush()
+			}
+		}
+	}
+}
+
+func endsSentence(token string) bool {
+	trimmed := strings.TrimSpace(token)
+	return strings.HasSuffix(trimmed, ".") || strings.HasSuffix(trimmed, "!") || strings.HasSuffix(trimmed, "?")
+}
+
+// Finalize flushes any audio Deepgram has buffered so far.
+func (p *DeepgramTTSProvider) Finalize() {
+	if p.client != nil {
+		p.client.Flush()
+	}
+}
+
+// Clear discards any audio Deepgram has buffered or is mid-synthesis on,
+// used when the user barges in and the client is about to flush its
+// playback queue.
+func (p *DeepgramTTSProvider) Clear() {
+	if p.client != nil {
+		p.client.Reset()
+	}
+}
+
+// Close tears down the Speak websocket.
+func (p *DeepgramTTSProvider) Close() error {
+	if p.client != nil {
+		p.client.Stop()
+	}
+	return nil
+}
+
+// SendAudioToClient forwards synthesized audio frames from a TTSProvider to
+// the browser as they arrive, stopping early if ctx is cancelled (e.g. the
+// user barged in on the current bot turn).
+func SendAudioToClient(ctx context.Context, botAudio <-chan []byte, writeChan chan utils.WebSocketPacket) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case audio, ok := <-botAudio:
+			if !ok {
+				return
+			}
+			writeChan <- utils.WebSocketPacket{Type: "audio", Audio: audio}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import "encoding/binary"
+
+// speechEnergyThreshold is the minimum average sample magnitude (of 16-bit
+// linear PCM audio) a frame needs to be treated as speech rather than
+// background noise or silence.
+const speechEnergyThreshold = 800
+
+// DetectsSpeech applies a simple energy-based voice-activity heuristic to a
+// frame of 16-bit linear PCM audio, returning true if the caller should
+// treat it as the start of speech (e.g. to trigger a barge-in interrupt).
+// It's a cheap local alternative to waiting on a provider's SpeechStarted
+// event.
+func DetectsSpeech(frame []byte) bool {
+	if len(frame) < 2 {
+		return false
+	}
+
+	var sum int64
+	samples := len(frame) / 2
+	for i := 0; i < samples; i++ {
+		sample := int16(binary.LittleEndian.Uint16(frame[i*2 : i*2+2]))
+		if sample < 0 {
+			sample = -sample
+		}
+		sum += int64(sample)
+	}
+
+	return sum/int64(samples) >= speechEnergyThreshold
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-websocket-server/utils"
+)
+
+// registerConversationRoutes wires up the REST endpoints for listing,
+// fetching, and exporting persisted conversations.
+func registerConversationRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /conversations", handleListConversations)
+	mux.HandleFunc("GET /conversations/{id}", handleGetConversation)
+	mux.HandleFunc("POST /conversations/{id}/export", handleExportConversation)
+}
+
+func handleListConversations(w http.ResponseWriter, r *http.Request) {
+	ids, err := utils.ListConversationIDs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ids)
+}
+
+func handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	messages, err := utils.LoadConversation(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// handleExportConversation returns a persisted conversation's transcript as
+// a caption track. The format query parameter selects "srt" or "vtt"
+// (default).
+func handleExportConversation(w http.ResponseWriter, r *http.Request) {
+	messages, err := utils.LoadConversation(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "srt" {
+		w.Header().Set("Content-Type", "application/x-subrip")
+		w.Write([]byte(utils.ToSRT(messages)))
+		return
+	}
+	w.Header().Set("Content-Type", "text/vtt")
+	w.Write([]byte(utils.ToWebVTT(messages)))
+}
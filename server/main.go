@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/websocket"
@@ -8,6 +9,7 @@ import (
 	"go-websocket-server/utils" // Import utils for DB initialization
 	"log"
 	"net/http"
+	"sync/atomic"
 )
 
 // Upgrader for handling WebSocket connections.
@@ -29,34 +31,104 @@ type Message struct {
 func main() {
 	// Initialize the SQLite database.
 	utils.InitDB("./conversation.db")
-	// Handle WebSocket connections at the /ws endpoint.
-	http.HandleFunc("/ws", handleWebSocket)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", handleWebSocket)
+	registerConversationRoutes(mux)
 
 	fmt.Println("Server is running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Fatal(http.ListenAndServe(":8080", mux))
+}
+
+// turn bundles the channels and STT/TTS providers backing a single
+// conversational turn, so handleWebSocket can tear one down and spin up a
+// fresh one without juggling half a dozen loose return values. ctx/cancel
+// scope the turn's bot pipeline (AskLlama + TTS) so a barge-in on the next
+// turn's audio can cut this one's reply short.
+type turn struct {
+	ctx              context.Context
+	cancel           context.CancelFunc
+	audioChunks      chan []byte
+	sttDone          chan struct{}
+	ttsDone          chan struct{}
+	userMessage      chan api.Transcript
+	botTextForClient chan string
+	botTextForTTS    chan string
+	stt              api.STTProvider
+	tts              api.TTSProvider
+	botActive        atomic.Bool
 }
 
-// These three goroutines handle sending data back to the user:
-// SendTranscriptToClient - Streams STT data from the deepgram websocket as a user message
+// makeTurn wires up a fresh STT/TTS provider pair (selected via
+// STT_PROVIDER/TTS_PROVIDER) and the goroutines that move their output onto
+// writeChan:
+// SendTranscriptToClient - Streams STT transcripts as a user message
 // SendTextToClient - Streams text from Groq as a bot message
-// SendAudioToClient - Sends audio from deepgram as a single file
-func makeTurnChannels(userTranscript chan string,
-	writeChan chan utils.WebSocketPacket,
-	stopChan chan bool) (userMessage chan string,
-	botTextForClient chan string,
-	botTextForTTS chan string,
-) {
-	userMessage = make(chan string) // Channel for entire user transcript as a single string
-	go api.SendTranscriptToClient(userTranscript, userMessage, writeChan, stopChan)
-
-	botAudio := make(chan []byte)
-	go api.SendAudioToClient(botAudio, writeChan)
-
-	botTextForClient = make(chan string)
-	botTextForTTS = make(chan string)
-	go api.BufferTextForTTS(botTextForTTS, botAudio)
-	go api.SendTextToClient(botTextForClient, writeChan)
-	return userMessage, botTextForClient, botTextForTTS
+// SendAudioToClient - Streams synthesized audio to the client
+func makeTurn(writeChan chan utils.WebSocketPacket, conversationID string) (*turn, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sttProvider, err := api.NewSTTProvider()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create STT provider: %w", err)
+	}
+	audioChunks := make(chan []byte)
+	transcripts, err := sttProvider.StreamAudio(ctx, audioChunks)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start STT stream: %w", err)
+	}
+
+	// Buffered so SendTranscriptToClient can hand off the turn's transcript
+	// and return even if audioEnd never arrives to read it.
+	userMessage := make(chan api.Transcript, 1)
+	// sttDone closes once SendTranscriptToClient's loop over transcripts
+	// ends (the STT stream finalized, e.g. its own silence timer, and
+	// closed), so a caller stuck trying to hand this turn more audio after
+	// that point has somewhere else to go instead of blocking forever.
+	sttDone := make(chan struct{})
+	go func() {
+		api.SendTranscriptToClient(transcripts, userMessage, writeChan, conversationID)
+		close(sttDone)
+	}()
+
+	ttsProvider, err := api.NewTTSProvider(writeChan)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create TTS provider: %w", err)
+	}
+	botTextForTTS := make(chan string)
+	botAudio, err := ttsProvider.Synthesize(ctx, botTextForTTS)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start TTS stream: %w", err)
+	}
+	// ttsDone closes once SendAudioToClient's loop over botAudio ends, so the
+	// goroutine that fires AskLlama can wait for the reply to actually finish
+	// playing out before cancelling this turn's ctx (see the comment at that
+	// call site for why cancelling any earlier is a bug).
+	ttsDone := make(chan struct{})
+	go func() {
+		api.SendAudioToClient(ctx, botAudio, writeChan)
+		close(ttsDone)
+	}()
+
+	botTextForClient := make(chan string)
+	go api.SendTextToClient(ctx, botTextForClient, writeChan)
+
+	return &turn{
+		ctx:              ctx,
+		cancel:           cancel,
+		audioChunks:      audioChunks,
+		sttDone:          sttDone,
+		ttsDone:          ttsDone,
+		userMessage:      userMessage,
+		botTextForClient: botTextForClient,
+		botTextForTTS:    botTextForTTS,
+		stt:              sttProvider,
+		tts:              ttsProvider,
+	}, nil
 }
 
 // handleWebSocket handles incoming WebSocket data packets.
@@ -66,21 +138,27 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
+	defer conn.Close() // Ensure the connection is closed when done.
+
 	// Single channel for outbound data on the websocket
 	writeChan := make(chan utils.WebSocketPacket)
 	go utils.WriteToWebsocket(writeChan, conn)
-	// Initialize Deepgram WebSocket connection
-	// and channel to hold the user transcript stream
-	userTranscript := make(chan string) // channel for streaming audio transcript
-	stopChan := make(chan bool)
-	// Part of initializing the deepgram connection is listening for packets
-	// and sending them to the userTranscript channel
-	deepgramConn, err := api.NewDeepgramConnection(userTranscript, stopChan)
+
+	// conversationID is whatever the client last told us. Each turn tags its
+	// transcripts with the value it had when the turn was opened, so the very
+	// first turn of a connection (opened before any message arrives) tags
+	// them with an empty ID.
+	var conversationID string
+	currentTurn, err := makeTurn(writeChan, conversationID)
 	if err != nil {
-		log.Fatalf("Failed to connect to Deepgram: %v", err)
+		log.Println("Failed to start turn:", err)
+		return
 	}
-	defer conn.Close() // Ensure the connection is closed when done.
-	userMessage, botTextForClient, botTextForTTS := makeTurnChannels(userTranscript, writeChan, stopChan)
+	// activeBotTurn is the most recent turn whose AskLlama+TTS pipeline is
+	// still in flight, i.e. the one a barge-in should cancel. It lags one
+	// turn behind currentTurn, which is already listening for the next
+	// utterance by the time a reply starts streaming.
+	var activeBotTurn *turn
 
 	for {
 		messageType, p, err := conn.ReadMessage()
@@ -95,21 +173,36 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				log.Println("Error unmarshaling message:", err)
 				continue
 			}
+			if message.Type == "resume" {
+				conversationID = message.ConversationID
+				history, err := utils.LoadConversation(message.ConversationID)
+				if err != nil {
+					log.Println("Error loading conversation for resume:", err)
+					continue
+				}
+				for _, m := range history {
+					writeChan <- utils.WebSocketPacket{
+						Type:           "history",
+						Text:           m.Text,
+						Words:          m.Words,
+						ConversationID: m.ConversationID,
+					}
+				}
+				continue
+			}
 			if message.Type == "audioEnd" {
 				log.Println("Received audioEnd message, waiting for final transcripts")
-				// Send a special Finalize message to Deepgram
-				log.Println("Finalizing deepgram transcription")
-				m := "{\"type\":\"Finalize\"}"
-				deepgramConn.WriteMessage(websocket.TextMessage, []byte(m))
-				log.Println("Sending stop signal..")
-				stopChan <- true // tell the listener to stop
-				close(stopChan)
-				// Wait for all transcripts to be processed and returned
-				// This is taking waaaay too long!!
+				currentTurn.stt.Finalize()
+				// Wait for all transcripts to be processed and returned. With
+				// the STT provider's auto-finalize timer this no longer
+				// depends on the client sending audioEnd promptly.
 				log.Println("Compiling full transcript...")
-				fullTranscript := <-userMessage
-				message.Text = fullTranscript
-				log.Printf("Full transcript is %s", fullTranscript)
+				fullTranscript := <-currentTurn.userMessage
+				message.Text = fullTranscript.Text
+				log.Printf("Full transcript is %s", fullTranscript.Text)
+				if err := utils.SaveMessage(message.ConversationID, "user", fullTranscript.Text, fullTranscript.Words); err != nil {
+					log.Println("Error saving user message:", err)
+				}
 			}
 			log.Printf("Sending text to llama: %s", message.Text)
 
@@ -117,34 +210,51 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				log.Println("Error: ConversationID is empty")
 				continue
 			}
-			go api.AskLlama(message.ConversationID, message.Text, botTextForClient, botTextForTTS)
-			// Re-open these two channels
+			conversationID = message.ConversationID
+
+			// Bind the turn that's about to answer to a local before handing it
+			// to the goroutine below: currentTurn gets reassigned to a fresh
+			// turn a few lines down, and a closure over the loop variable
+			// would end up driving whichever turn is current by the time the
+			// goroutine runs, not the one that actually fired AskLlama.
+			t := currentTurn
+			activeBotTurn = t
+			t.botActive.Store(true)
+			go func() {
+				api.AskLlama(t.ctx, message.ConversationID, message.Text, t.botTextForClient, t.botTextForTTS)
+				// Don't cancel ctx here: AskLlama closing botTextForTTS is
+				// what drives streamTokens' clean shutdown (flush, then
+				// close), and cancelling while that's still in flight races
+				// its select and can cut the reply's audio short. cancel is
+				// reserved for barge-in; here we only wait for the reply to
+				// finish playing out so ctx's resources don't leak.
+				<-t.ttsDone
+				t.botActive.Store(false)
+				t.cancel()
+			}()
+
+			// Start a fresh turn to capture the user's next utterance while
+			// the bot's reply streams out above.
 			log.Println("Re-opening channels")
-			userTranscript = make(chan string)
-			stopChan = make(chan bool)
-			userMessage, botTextForClient, botTextForTTS = makeTurnChannels(userTranscript, writeChan, stopChan)
-			deepgramConn, err = api.NewDeepgramConnection(userTranscript, stopChan)
+			currentTurn, err = makeTurn(writeChan, conversationID)
+			if err != nil {
+				log.Println("Failed to start next turn:", err)
+				return
+			}
 
 		} else if messageType == websocket.BinaryMessage {
 			log.Printf("Received %d bytes of audio data", len(p))
+			select {
+			case currentTurn.audioChunks <- p:
+			case <-currentTurn.sttDone:
+				log.Println("Dropping audio frame: current turn's STT stream already finalized")
+			}
 
-			// Send the audio chunk to Deepgram directly
-			err := deepgramConn.WriteMessage(websocket.BinaryMessage, p)
-			if err != nil {
-				// Reconnect and try again
-				deepgramConn, err = api.NewDeepgramConnection(userTranscript, stopChan)
-				if err != nil {
-					log.Fatalf("Failed to connect to Deepgram: %v", err)
-				} else {
-					err := deepgramConn.WriteMessage(websocket.BinaryMessage, p)
-					if err != nil {
-						log.Fatal("Failed to re-connect to Deepgram:", err)
-					} else {
-						log.Println("Successfully sent chunk to deepgram on the second try")
-					}
-				}
-			} else {
-				log.Println("Successfully sent chunk to Deepgram")
+			if activeBotTurn != nil && activeBotTurn.botActive.Load() && api.DetectsSpeech(p) {
+				log.Println("Barge-in detected, cancelling current bot turn")
+				activeBotTurn.cancel()
+				activeBotTurn.tts.Clear()
+				writeChan <- utils.WebSocketPacket{Type: "interrupt"}
 			}
 		}
 	}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// captionCue is one timed caption entry derived from a persisted Message.
+type captionCue struct {
+	start float64
+	end   float64
+	text  string
+}
+
+// captionCues turns a message into caption cues, using its word-level
+// timings when available and falling back to a single untimed cue when not
+// (e.g. for text-only messages).
+func captionCues(m Message) []captionCue {
+	if len(m.Words) == 0 {
+		return []captionCue{{text: m.Text}}
+	}
+	return []captionCue{{
+		start: m.Words[0].Start,
+		end:   m.Words[len(m.Words)-1].End,
+		text:  m.Text,
+	}}
+}
+
+// ToWebVTT renders a conversation's messages as a WebVTT caption track.
+func ToWebVTT(messages []Message) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, m := range messages {
+		for _, cue := range captionCues(m) {
+			fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatVTTTimestamp(cue.start), formatVTTTimestamp(cue.end), cue.text)
+		}
+	}
+	return b.String()
+}
+
+// ToSRT renders a conversation's messages as a SubRip (SRT) caption track.
+func ToSRT(messages []Message) string {
+	var b strings.Builder
+	index := 1
+	for _, m := range messages {
+		for _, cue := range captionCues(m) {
+			fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", index, formatSRTTimestamp(cue.start), formatSRTTimestamp(cue.end), cue.text)
+			index++
+		}
+	}
+	return b.String()
+}
+
+func formatVTTTimestamp(seconds float64) string {
+	h, m, s, ms := splitSeconds(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
+}
+
+func formatSRTTimestamp(seconds float64) string {
+	h, m, s, ms := splitSeconds(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
+}
+
+func splitSeconds(seconds float64) (h, m, s, ms int) {
+	whole := int(seconds)
+	return whole / 3600, (whole % 3600) / 60, whole % 60, int((seconds - float64(whole)) * 1000)
+}
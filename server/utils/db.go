@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DB is the process-wide SQLite handle opened by InitDB.
+var DB *sql.DB
+
+const createMessagesTable = `
+CREATE TABLE IF NOT EXISTS messages (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	conversation_id TEXT NOT NULL,
+	role            TEXT NOT NULL,
+	text            TEXT NOT NULL,
+	words           TEXT
+);
+`
+
+// migrateMessagesTable adds columns introduced after the table's initial
+// creation, so databases created by earlier versions of this schema pick
+// them up without a destructive migration.
+func migrateMessagesTable(db *sql.DB) error {
+	rows, err := db.Query(`PRAGMA table_info(messages)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	newColumns := []struct {
+		name string
+		ddl  string
+	}{
+		{"ts", "ALTER TABLE messages ADD COLUMN ts INTEGER"},
+		{"audio_ref", "ALTER TABLE messages ADD COLUMN audio_ref TEXT"},
+	}
+	for _, col := range newColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.Exec(col.ddl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InitDB opens (creating if necessary) the SQLite database at path, applies
+// the messages schema and any pending migrations, and assigns the handle to
+// DB.
+func InitDB(path string) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(createMessagesTable); err != nil {
+		log.Fatalf("Failed to initialize schema: %v", err)
+	}
+	if err := migrateMessagesTable(db); err != nil {
+		log.Fatalf("Failed to migrate schema: %v", err)
+	}
+	DB = db
+}
+
+// Message is one persisted turn of a conversation.
+type Message struct {
+	ID             int64
+	ConversationID string
+	Role           string
+	Text           string
+	Words          []Word
+	Timestamp      time.Time
+	AudioRef       string
+}
+
+// SaveMessage persists one finalized turn of a conversation, along with its
+// word-level timing/confidence data (if any) as a JSON blob, so the turn
+// survives page reloads and can be replayed or exported later.
+func SaveMessage(conversationID, role, text string, words []Word) error {
+	wordsJSON, err := json.Marshal(words)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(
+		`INSERT INTO messages (conversation_id, role, text, words, ts) VALUES (?, ?, ?, ?, ?)`,
+		conversationID, role, text, string(wordsJSON), time.Now().Unix(),
+	)
+	return err
+}
+
+// LoadConversation returns every message persisted for conversationID, in
+// the order they were saved.
+func LoadConversation(conversationID string) ([]Message, error) {
+	rows, err := DB.Query(
+		`SELECT id, conversation_id, role, text, words, ts, audio_ref FROM messages WHERE conversation_id = ? ORDER BY id ASC`,
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		var wordsJSON string
+		var ts int64
+		var audioRef sql.NullString
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Text, &wordsJSON, &ts, &audioRef); err != nil {
+			return nil, err
+		}
+		if wordsJSON != "" {
+			if err := json.Unmarshal([]byte(wordsJSON), &m.Words); err != nil {
+				return nil, err
+			}
+		}
+		m.Timestamp = time.Unix(ts, 0)
+		m.AudioRef = audioRef.String
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// ListConversationIDs returns the distinct conversation IDs with at least
+// one persisted message, most recently active first.
+func ListConversationIDs() ([]string, error) {
+	rows, err := DB.Query(
+		`SELECT conversation_id FROM messages GROUP BY conversation_id ORDER BY MAX(ts) DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
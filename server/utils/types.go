@@ -0,0 +1,25 @@
+package utils
+
+// Word is one word-level timing/confidence entry from a speech-to-text
+// provider's transcript.
+type Word struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+	Punctuated string  `json:"punctuated"`
+}
+
+// WebSocketPacket is the envelope written to the browser over the /ws
+// connection. Every outbound goroutine (transcript, bot text, bot audio)
+// funnels its payloads through this type so WriteToWebsocket can serialize
+// them uniformly. Type discriminates the payload, e.g. "interim"/"final"
+// for transcripts, "botMessage" for LLM tokens, "audio" for synthesized
+// speech, and "interrupt" for barge-in.
+type WebSocketPacket struct {
+	Type           string `json:"type"`
+	Text           string `json:"text,omitempty"`
+	Audio          []byte `json:"audio,omitempty"`
+	ConversationID string `json:"conversationId,omitempty"`
+	Words          []Word `json:"words,omitempty"`
+	Channel        int    `json:"channel,omitempty"`
+}
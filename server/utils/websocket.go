@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// WriteToWebsocket drains packets off writeChan and writes each one to conn
+// as a JSON text frame until the channel is closed or a write fails.
+func WriteToWebsocket(writeChan chan WebSocketPacket, conn *websocket.Conn) {
+	for packet := range writeChan {
+		if err := conn.WriteJSON(packet); err != nil {
+			log.Println("Error writing to websocket:", err)
+			return
+		}
+	}
+}